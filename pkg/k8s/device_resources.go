@@ -0,0 +1,158 @@
+package k8s
+
+import (
+	"github.com/atlassian/escalator/pkg/k8s/podresources"
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DeviceSource supplies the kubelet's authoritative allocatable devices for an arbitrary
+// node, e.g. backed by podresources.NodeClientSource. Implementations should return an
+// error when the data can't be fetched (e.g. escalator running off-node, without access to
+// the node's PodResources socket), so callers can fall back to node.Status.Allocatable
+// alone.
+type DeviceSource interface {
+	GetAllocatableResources(nodeName string) (podresources.AllocatableResources, error)
+}
+
+var _ DeviceSource = (*podresources.NodeClientSource)(nil)
+
+// DeviceOptions configures the optional kubelet-PodResources-backed device accounting
+// used by GetNodeAvailableResources. A nil DeviceOptions, or a nil Source, falls back to
+// node.Status.Allocatable alone, which doesn't reflect device-exclusive allocations such
+// as pinned CPUs, hugepages, or vendor devices like nvidia.com/gpu.
+type DeviceOptions struct {
+	Source DeviceSource
+}
+
+// deviceResourceNames are the v1.Node.Status.Allocatable resource names handled by CPU and
+// memory accounting elsewhere; anything else (nvidia.com/gpu, hugepages-2Mi, ...) is a
+// device resource.
+var deviceResourceNames = map[v1.ResourceName]bool{
+	v1.ResourceCPU:              true,
+	v1.ResourceMemory:           true,
+	v1.ResourceStorage:          true,
+	v1.ResourceEphemeralStorage: true,
+	v1.ResourcePods:             true,
+}
+
+func isDeviceResourceName(name v1.ResourceName) bool {
+	return !deviceResourceNames[name]
+}
+
+// podDeviceRequests sums pod's container-level requests for device resources (i.e.
+// anything other than CPU/memory/storage/pods), keyed by resource name.
+func podDeviceRequests(pod *v1.Pod) map[v1.ResourceName]resource.Quantity {
+	sums := make(map[v1.ResourceName]resource.Quantity)
+	for _, container := range pod.Spec.Containers {
+		for name, qty := range container.Resources.Requests {
+			if !isDeviceResourceName(name) {
+				continue
+			}
+			addQuantity(sums, name, qty)
+		}
+	}
+	return sums
+}
+
+// addDeviceRequests adds pod's device requests onto item.Devices, initializing it if
+// necessary.
+func addDeviceRequests(item *ResourceItem, pod *v1.Pod) {
+	requests := podDeviceRequests(pod)
+	if len(requests) == 0 {
+		return
+	}
+	if item.Devices == nil {
+		item.Devices = make(map[v1.ResourceName]resource.Quantity, len(requests))
+	}
+	for name, qty := range requests {
+		addQuantity(item.Devices, name, qty)
+	}
+}
+
+func addQuantity(sums map[v1.ResourceName]resource.Quantity, name v1.ResourceName, qty resource.Quantity) {
+	if existing, ok := sums[name]; ok {
+		existing.Add(qty)
+		sums[name] = existing
+	} else {
+		sums[name] = qty.DeepCopy()
+	}
+}
+
+// memoryManagerGenericType is the MemoryType the kubelet's Memory Manager reports for
+// regular (non-hugepage) memory, as opposed to e.g. "hugepages-2Mi".
+const memoryManagerGenericType = "memory"
+
+// deviceAvailability returns the devices still available on node, using opts' DeviceSource
+// for authoritative kubelet-reported allocatable devices and memory blocks minus the
+// device/hugepage requests of the pods assigned to it. devices is nil when opts is nil, has
+// no Source, or the Source lookup fails. hasCPU/MemoryAllocatableOverride report whether the
+// kubelet's CPU/Memory Manager reservations (CPUIDs, the generic memory block) should
+// replace node.Status.Allocatable's CPU/memory baseline.
+func deviceAvailability(node *v1.Node, pods []*v1.Pod, opts *DeviceOptions, exclusionPolicy *PodExclusionPolicy) (devices map[v1.ResourceName]resource.Quantity, cpuMilliAllocatable int64, hasCPUAllocatableOverride bool, memoryBytesAllocatable int64, hasMemoryAllocatableOverride bool) {
+	if opts == nil || opts.Source == nil {
+		return nil, 0, false, 0, false
+	}
+
+	allocatable, err := opts.Source.GetAllocatableResources(node.Name)
+	if err != nil {
+		return nil, 0, false, 0, false
+	}
+
+	devices = make(map[v1.ResourceName]resource.Quantity, len(allocatable.Devices)+len(allocatable.Memory))
+	for name, ids := range allocatable.Devices {
+		devices[v1.ResourceName(name)] = *resource.NewQuantity(int64(len(ids)), resource.DecimalSI)
+	}
+
+	if len(allocatable.CPUIDs) > 0 {
+		hasCPUAllocatableOverride = true
+		cpuMilliAllocatable = int64(len(allocatable.CPUIDs)) * 1000
+	}
+
+	for memType, size := range allocatable.Memory {
+		if memType == memoryManagerGenericType {
+			hasMemoryAllocatableOverride = true
+			memoryBytesAllocatable = int64(size)
+			continue
+		}
+		devices[v1.ResourceName(memType)] = *resource.NewQuantity(int64(size), resource.BinarySI)
+	}
+
+	for _, pod := range pods {
+		if !isPodUsingNodeResources(pod, exclusionPolicy) {
+			continue
+		}
+		for name, qty := range podDeviceRequests(pod) {
+			if existing, ok := devices[name]; ok {
+				existing.Sub(qty)
+				devices[name] = existing
+			}
+		}
+	}
+
+	return devices, cpuMilliAllocatable, hasCPUAllocatableOverride, memoryBytesAllocatable, hasMemoryAllocatableOverride
+}
+
+// trackLargestDeviceRequest updates largest with pod's device requests if pod requests
+// more of some device resource than the currently tracked pending pod, so e.g. a pending
+// pod requesting several GPUs with otherwise small CPU/memory requests is still surfaced
+// as needing a GPU-capable node.
+func trackLargestDeviceRequest(largest map[v1.ResourceName]ResourceItem, pod *v1.Pod, podResources scheduler.PodResource) map[v1.ResourceName]ResourceItem {
+	requests := podDeviceRequests(pod)
+	if len(requests) == 0 {
+		return largest
+	}
+	if largest == nil {
+		largest = make(map[v1.ResourceName]ResourceItem, len(requests))
+	}
+	for name, qty := range requests {
+		if existing, tracked := largest[name]; tracked && existing.Devices[name].Cmp(qty) >= 0 {
+			continue
+		}
+		item := newResourceItem(podResources.MilliCPU, podResources.Memory)
+		item.Devices = requests
+		largest[name] = item
+	}
+	return largest
+}
@@ -0,0 +1,130 @@
+package k8s
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/atlassian/escalator/pkg/k8s/podresources"
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func podWithGPU(name, cpu string, gpus string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{
+				{
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{
+							v1.ResourceCPU: resource.MustParse(cpu),
+							"nvidia.com/gpu": resource.MustParse(gpus),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPodDeviceRequests(t *testing.T) {
+	pod := podWithGPU("pod", "1", "2")
+
+	got := podDeviceRequests(pod)
+	qty, ok := got["nvidia.com/gpu"]
+	if !ok || qty.Value() != 2 {
+		t.Errorf("podDeviceRequests() = %v, want nvidia.com/gpu: 2", got)
+	}
+	if _, ok := got[v1.ResourceCPU]; ok {
+		t.Errorf("podDeviceRequests() should not include CPU, got %v", got)
+	}
+}
+
+type fakeDeviceSource struct {
+	allocatable podresources.AllocatableResources
+	err         error
+}
+
+func (f *fakeDeviceSource) GetAllocatableResources(nodeName string) (podresources.AllocatableResources, error) {
+	return f.allocatable, f.err
+}
+
+func TestDeviceAvailability(t *testing.T) {
+	node := nodeWithCPU("node", "4")
+
+	t.Run("nil opts returns no availability", func(t *testing.T) {
+		devices, cpu, hasCPU, mem, hasMem := deviceAvailability(node, nil, nil, nil)
+		if devices != nil || cpu != 0 || hasCPU || mem != 0 || hasMem {
+			t.Errorf("deviceAvailability() = %v, %v, %v, %v, %v, want nil, 0, false, 0, false", devices, cpu, hasCPU, mem, hasMem)
+		}
+	})
+
+	t.Run("source error returns no availability", func(t *testing.T) {
+		opts := &DeviceOptions{Source: &fakeDeviceSource{err: fmt.Errorf("unreachable")}}
+		devices, cpu, hasCPU, mem, hasMem := deviceAvailability(node, nil, opts, nil)
+		if devices != nil || cpu != 0 || hasCPU || mem != 0 || hasMem {
+			t.Errorf("deviceAvailability() = %v, %v, %v, %v, %v, want nil, 0, false, 0, false", devices, cpu, hasCPU, mem, hasMem)
+		}
+	})
+
+	t.Run("subtracts device requests of assigned pods", func(t *testing.T) {
+		opts := &DeviceOptions{Source: &fakeDeviceSource{allocatable: podresources.AllocatableResources{
+			Devices: map[string][]string{"nvidia.com/gpu": {"gpu0", "gpu1", "gpu2", "gpu3"}},
+		}}}
+		pods := []*v1.Pod{scheduledPodOnNode("pod", "node", "1")}
+		pods[0].Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+
+		devices, _, _, _, _ := deviceAvailability(node, pods, opts, nil)
+		if got := devices["nvidia.com/gpu"].Value(); got != 3 {
+			t.Errorf("deviceAvailability() nvidia.com/gpu available = %d, want 3", got)
+		}
+	})
+
+	t.Run("CPUIDs override the node's allocatable CPU baseline", func(t *testing.T) {
+		opts := &DeviceOptions{Source: &fakeDeviceSource{allocatable: podresources.AllocatableResources{
+			CPUIDs: []int64{0, 1, 2},
+		}}}
+		_, cpu, hasCPU, _, _ := deviceAvailability(node, nil, opts, nil)
+		if !hasCPU || cpu != 3000 {
+			t.Errorf("deviceAvailability() cpuMilliAllocatable, hasCPU = %d, %v, want 3000, true", cpu, hasCPU)
+		}
+	})
+
+	t.Run("generic memory manager block overrides the node's allocatable memory baseline", func(t *testing.T) {
+		opts := &DeviceOptions{Source: &fakeDeviceSource{allocatable: podresources.AllocatableResources{
+			Memory: map[string]uint64{"memory": 8 << 30},
+		}}}
+		_, _, _, mem, hasMem := deviceAvailability(node, nil, opts, nil)
+		if !hasMem || mem != 8<<30 {
+			t.Errorf("deviceAvailability() memoryBytesAllocatable, hasMem = %d, %v, want %d, true", mem, hasMem, 8<<30)
+		}
+	})
+
+	t.Run("hugepage blocks are tracked as devices and reduced by pod requests", func(t *testing.T) {
+		opts := &DeviceOptions{Source: &fakeDeviceSource{allocatable: podresources.AllocatableResources{
+			Memory: map[string]uint64{"hugepages-2Mi": 256 << 20},
+		}}}
+		pods := []*v1.Pod{scheduledPodOnNode("pod", "node", "1")}
+		pods[0].Spec.Containers[0].Resources.Requests["hugepages-2Mi"] = resource.MustParse("64Mi")
+
+		devices, _, _, _, _ := deviceAvailability(node, pods, opts, nil)
+		if got := devices["hugepages-2Mi"].Value(); got != 192<<20 {
+			t.Errorf("deviceAvailability() hugepages-2Mi available = %d, want %d", got, 192<<20)
+		}
+	})
+}
+
+func TestTrackLargestDeviceRequest(t *testing.T) {
+	small := podWithGPU("small", "1", "1")
+	large := podWithGPU("large", "1", "4")
+
+	largest := trackLargestDeviceRequest(nil, small, scheduler.ComputePodResourceRequest(small))
+	largest = trackLargestDeviceRequest(largest, large, scheduler.ComputePodResourceRequest(large))
+
+	got, ok := largest["nvidia.com/gpu"]
+	if !ok || got.Devices["nvidia.com/gpu"].Value() != 4 {
+		t.Errorf("trackLargestDeviceRequest() = %v, want the 4-GPU pod tracked", largest)
+	}
+}
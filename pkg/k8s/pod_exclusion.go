@@ -0,0 +1,87 @@
+package k8s
+
+import v1 "k8s.io/api/core/v1"
+
+// PodExclusionPolicy configures additional pods that should be excluded from capacity
+// accounting, on top of the default "scheduled and Pending/Running" check. Passing nil
+// wherever a policy is accepted keeps today's behaviour.
+type PodExclusionPolicy struct {
+	// ExcludeUnreachableTerminating excludes pods marked for deletion (non-nil
+	// DeletionTimestamp) whose node carries a node.kubernetes.io/unreachable:NoExecute
+	// taint. Such pods will never consume resources again, but the apiserver can be slow
+	// to finish removing them, and until this is set they otherwise block scale-up
+	// decisions by still counting as using node resources.
+	ExcludeUnreachableTerminating bool
+	// Nodes is a lookup of node name to node, used to evaluate
+	// ExcludeUnreachableTerminating. CalculateNodesCapacity populates this itself from its
+	// own nodes argument; set it directly when calling CalculatePodsRequestedUsage, or
+	// GetNodeAvailableResources on a single node without going through
+	// CalculateNodesCapacity.
+	Nodes map[string]*v1.Node
+	// ExcludeAnnotations excludes any pod whose annotations contain one of these
+	// key/value pairs.
+	ExcludeAnnotations map[string]string
+	// ExcludeLabels excludes any pod whose labels contain one of these key/value pairs.
+	ExcludeLabels map[string]string
+}
+
+// shouldExcludePod returns whether pod should be excluded from capacity accounting under
+// policy. A nil policy excludes nothing.
+func shouldExcludePod(pod *v1.Pod, policy *PodExclusionPolicy) bool {
+	if policy == nil {
+		return false
+	}
+
+	if policy.ExcludeUnreachableTerminating && isUnreachableTerminatingPod(pod, policy.Nodes) {
+		return true
+	}
+
+	for key, value := range policy.ExcludeAnnotations {
+		if pod.ObjectMeta.Annotations[key] == value {
+			return true
+		}
+	}
+	for key, value := range policy.ExcludeLabels {
+		if pod.ObjectMeta.Labels[key] == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nodeLookup builds a node name to node lookup from nodes, for PodExclusionPolicy.Nodes.
+func nodeLookup(nodes []*v1.Node) map[string]*v1.Node {
+	lookup := make(map[string]*v1.Node, len(nodes))
+	for _, node := range nodes {
+		lookup[node.Name] = node
+	}
+	return lookup
+}
+
+// isUnreachableTerminatingPod returns whether pod is marked for deletion and scheduled to
+// a node tainted node.kubernetes.io/unreachable:NoExecute, i.e. a pod that will never
+// consume resources again regardless of how long its deletion is still pending.
+func isUnreachableTerminatingPod(pod *v1.Pod, nodes map[string]*v1.Node) bool {
+	if pod.ObjectMeta.DeletionTimestamp == nil {
+		return false
+	}
+
+	node, ok := nodes[pod.Spec.NodeName]
+	if !ok {
+		return false
+	}
+
+	return nodeHasUnreachableTaint(node)
+}
+
+// nodeHasUnreachableTaint returns whether node carries the
+// node.kubernetes.io/unreachable taint with a NoExecute effect.
+func nodeHasUnreachableTaint(node *v1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == v1.TaintNodeUnreachable && taint.Effect == v1.TaintEffectNoExecute {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func unreachableNode(name string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.NodeSpec{
+			Taints: []v1.Taint{
+				{Key: v1.TaintNodeUnreachable, Effect: v1.TaintEffectNoExecute},
+			},
+		},
+	}
+}
+
+func reachableNode(name string) *v1.Node {
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func podOnNode(nodeName string, terminating bool) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod"},
+		Spec:       v1.PodSpec{NodeName: nodeName},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+	if terminating {
+		now := metav1.NewTime(time.Now())
+		pod.ObjectMeta.DeletionTimestamp = &now
+	}
+	return pod
+}
+
+func TestIsUnreachableTerminatingPod(t *testing.T) {
+	nodes := map[string]*v1.Node{
+		"unreachable": unreachableNode("unreachable"),
+		"reachable":   reachableNode("reachable"),
+	}
+
+	tests := []struct {
+		name        string
+		pod         *v1.Pod
+		want        bool
+	}{
+		{"terminating on unreachable node", podOnNode("unreachable", true), true},
+		{"terminating on reachable node", podOnNode("reachable", true), false},
+		{"running on unreachable node", podOnNode("unreachable", false), false},
+		{"running on reachable node", podOnNode("reachable", false), false},
+		{"terminating on unknown node", podOnNode("missing", true), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUnreachableTerminatingPod(tt.pod, nodes); got != tt.want {
+				t.Errorf("isUnreachableTerminatingPod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldExcludePod_UnreachableTerminating(t *testing.T) {
+	nodes := map[string]*v1.Node{
+		"unreachable": unreachableNode("unreachable"),
+		"reachable":   reachableNode("reachable"),
+	}
+
+	policy := &PodExclusionPolicy{
+		ExcludeUnreachableTerminating: true,
+		Nodes:                         nodes,
+	}
+
+	tests := []struct {
+		name string
+		pod  *v1.Pod
+		want bool
+	}{
+		{"terminating on unreachable node is excluded", podOnNode("unreachable", true), true},
+		{"terminating on reachable node is not excluded", podOnNode("reachable", true), false},
+		{"running on unreachable node is not excluded", podOnNode("unreachable", false), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldExcludePod(tt.pod, policy); got != tt.want {
+				t.Errorf("shouldExcludePod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("policy without ExcludeUnreachableTerminating excludes nothing", func(t *testing.T) {
+		disabled := &PodExclusionPolicy{Nodes: nodes}
+		if shouldExcludePod(podOnNode("unreachable", true), disabled) {
+			t.Error("expected pod not to be excluded when ExcludeUnreachableTerminating is false")
+		}
+	})
+
+	t.Run("nil policy excludes nothing", func(t *testing.T) {
+		if shouldExcludePod(podOnNode("unreachable", true), nil) {
+			t.Error("expected pod not to be excluded when policy is nil")
+		}
+	})
+}
+
+func TestCalculateNodesCapacity_BuildsNodeLookupItself(t *testing.T) {
+	node := unreachableNode("node")
+	node.Status.Allocatable = v1.ResourceList{
+		v1.ResourceCPU:  resource.MustParse("4"),
+		v1.ResourcePods: resource.MustParse("10"),
+	}
+	pod := scheduledPodOnNode("pod", "node", "2")
+	now := metav1.NewTime(time.Now())
+	pod.ObjectMeta.DeletionTimestamp = &now
+
+	opts := &CapacityOptions{Exclusion: &PodExclusionPolicy{ExcludeUnreachableTerminating: true}}
+
+	capacity, err := CalculateNodesCapacity([]*v1.Node{node}, []*v1.Pod{pod}, opts)
+	if err != nil {
+		t.Fatalf("CalculateNodesCapacity() error = %v", err)
+	}
+	if got := capacity.NodeUtilization["node"].CPU; got != 0 {
+		t.Errorf("CalculateNodesCapacity() node CPU utilization = %v, want 0: the terminating pod on the unreachable node should be excluded even though opts.Exclusion.Nodes was never populated by the caller", got)
+	}
+}
+
+func TestShouldExcludePod_AnnotationsAndLabels(t *testing.T) {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"escalator/exclude": "true"},
+			Labels:      map[string]string{"tier": "excluded"},
+		},
+	}
+
+	if !shouldExcludePod(pod, &PodExclusionPolicy{ExcludeAnnotations: map[string]string{"escalator/exclude": "true"}}) {
+		t.Error("expected pod to be excluded by matching annotation")
+	}
+	if !shouldExcludePod(pod, &PodExclusionPolicy{ExcludeLabels: map[string]string{"tier": "excluded"}}) {
+		t.Error("expected pod to be excluded by matching label")
+	}
+	if shouldExcludePod(pod, &PodExclusionPolicy{ExcludeAnnotations: map[string]string{"escalator/exclude": "false"}}) {
+		t.Error("expected pod not to be excluded when annotation value doesn't match")
+	}
+}
@@ -0,0 +1,149 @@
+// Package podresources talks to the kubelet's PodResources gRPC API to retrieve
+// authoritative allocation info for device-exclusive resources - pinned CPUs (CPU
+// Manager), memory blocks (Memory Manager), and vendor devices (e.g. nvidia.com/gpu) -
+// that node.Status.Allocatable alone doesn't reflect.
+package podresources
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+// DefaultSocketPath is the default location of the kubelet's PodResources gRPC socket.
+const DefaultSocketPath = "/var/lib/kubelet/pod-resources/kubelet.sock"
+
+// dialTimeout bounds how long Client waits to dial or call the kubelet socket.
+const dialTimeout = 5 * time.Second
+
+// Client talks to the kubelet's PodResources gRPC API over a unix socket.
+type Client struct {
+	conn   *grpc.ClientConn
+	client podresourcesapi.PodResourcesListerClient
+}
+
+// NewClient dials the kubelet's PodResources socket at socketPath (DefaultSocketPath in
+// production). Callers running off-node, where the socket isn't reachable, should treat a
+// non-nil error here as a signal to fall back to API-server-based accounting.
+func NewClient(socketPath string) (*Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, socketPath,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", addr)
+		}),
+		grpc.WithBlock(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("dialing kubelet pod-resources socket %q: %w", socketPath, err)
+	}
+
+	return &Client{
+		conn:   conn,
+		client: podresourcesapi.NewPodResourcesListerClient(conn),
+	}, nil
+}
+
+// Close releases the underlying gRPC connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// ContainerResources is the CPUs and devices the kubelet has allocated to a container.
+type ContainerResources struct {
+	Name string
+	// CPUIDs are the exclusive CPU cores allocated by the CPU Manager static policy, if
+	// any.
+	CPUIDs []int64
+	// Devices maps a vendor device resource name (e.g. "nvidia.com/gpu") to the
+	// allocated device IDs.
+	Devices map[string][]string
+}
+
+// PodResources is the kubelet-allocated resources of a single pod's containers.
+type PodResources struct {
+	Namespace  string
+	Name       string
+	Containers []ContainerResources
+}
+
+// ListPodResources returns the kubelet's authoritative view of what every pod on this
+// node has actually been allocated.
+func (c *Client) ListPodResources() ([]PodResources, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	resp, err := c.client.List(ctx, &podresourcesapi.ListPodResourcesRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("listing pod resources: %w", err)
+	}
+
+	pods := make([]PodResources, 0, len(resp.GetPodResources()))
+	for _, pod := range resp.GetPodResources() {
+		containers := make([]ContainerResources, 0, len(pod.GetContainers()))
+		for _, container := range pod.GetContainers() {
+			containers = append(containers, ContainerResources{
+				Name:    container.GetName(),
+				CPUIDs:  container.GetCpuIds(),
+				Devices: deviceIDsByResourceName(container.GetDevices()),
+			})
+		}
+		pods = append(pods, PodResources{
+			Namespace:  pod.GetNamespace(),
+			Name:       pod.GetName(),
+			Containers: containers,
+		})
+	}
+
+	return pods, nil
+}
+
+// AllocatableResources is a node's total allocatable CPUs, memory blocks and devices as
+// tracked by the kubelet, reflecting reservations (e.g. the CPU/Memory Manager's reserved
+// sets) that the API server's node status doesn't expose.
+type AllocatableResources struct {
+	CPUIDs  []int64
+	Memory  map[string]uint64
+	Devices map[string][]string
+}
+
+// GetAllocatableResources returns the kubelet's view of this node's allocatable CPUs,
+// memory blocks (e.g. Memory-Manager-pinned hugepages) and devices.
+func (c *Client) GetAllocatableResources() (AllocatableResources, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	resp, err := c.client.GetAllocatableResources(ctx, &podresourcesapi.AllocatableResourcesRequest{})
+	if err != nil {
+		return AllocatableResources{}, fmt.Errorf("getting allocatable resources: %w", err)
+	}
+
+	return AllocatableResources{
+		CPUIDs:  resp.GetCpuIds(),
+		Memory:  memoryBytesByType(resp.GetMemory()),
+		Devices: deviceIDsByResourceName(resp.GetDevices()),
+	}, nil
+}
+
+func deviceIDsByResourceName(devices []*podresourcesapi.ContainerDevices) map[string][]string {
+	ret := make(map[string][]string)
+	for _, device := range devices {
+		ret[device.GetResourceName()] = append(ret[device.GetResourceName()], device.GetDeviceIds()...)
+	}
+	return ret
+}
+
+func memoryBytesByType(memory []*podresourcesapi.ContainerMemory) map[string]uint64 {
+	ret := make(map[string]uint64)
+	for _, block := range memory {
+		ret[block.GetMemoryType()] += block.GetSize_()
+	}
+	return ret
+}
@@ -0,0 +1,31 @@
+package podresources
+
+import (
+	"reflect"
+	"testing"
+
+	podresourcesapi "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+func TestDeviceIDsByResourceName(t *testing.T) {
+	devices := []*podresourcesapi.ContainerDevices{
+		{ResourceName: "nvidia.com/gpu", DeviceIds: []string{"gpu0"}},
+		{ResourceName: "nvidia.com/gpu", DeviceIds: []string{"gpu1"}},
+		{ResourceName: "hugepages-2Mi", DeviceIds: []string{"page0"}},
+	}
+
+	got := deviceIDsByResourceName(devices)
+	want := map[string][]string{
+		"nvidia.com/gpu": {"gpu0", "gpu1"},
+		"hugepages-2Mi":  {"page0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deviceIDsByResourceName() = %v, want %v", got, want)
+	}
+}
+
+func TestDeviceIDsByResourceName_Empty(t *testing.T) {
+	if got := deviceIDsByResourceName(nil); len(got) != 0 {
+		t.Errorf("deviceIDsByResourceName(nil) = %v, want empty", got)
+	}
+}
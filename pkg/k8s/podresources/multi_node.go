@@ -0,0 +1,71 @@
+package podresources
+
+import (
+	"fmt"
+	"sync"
+)
+
+// NodeClientSource adapts per-node Client connections to serve arbitrary node names, since
+// the PodResources gRPC socket is host-local and a single Client can only ever answer for
+// the node escalator itself is running on. resolveSocketPath maps a node name to the path of
+// a unix socket that reaches that node's kubelet PodResources API (NewClient always dials it
+// as a unix socket); NodeClientSource dials it on demand and caches the result.
+type NodeClientSource struct {
+	resolveSocketPath func(nodeName string) (string, error)
+
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+// NewNodeClientSource returns a NodeClientSource that dials a node's socket address via
+// resolveSocketPath the first time it's asked about that node.
+func NewNodeClientSource(resolveSocketPath func(nodeName string) (string, error)) *NodeClientSource {
+	return &NodeClientSource{
+		resolveSocketPath: resolveSocketPath,
+		clients:           make(map[string]*Client),
+	}
+}
+
+// GetAllocatableResources returns nodeName's allocatable resources via its cached (or newly
+// dialed) Client.
+func (s *NodeClientSource) GetAllocatableResources(nodeName string) (AllocatableResources, error) {
+	client, err := s.clientFor(nodeName)
+	if err != nil {
+		return AllocatableResources{}, err
+	}
+	return client.GetAllocatableResources()
+}
+
+func (s *NodeClientSource) clientFor(nodeName string) (*Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if client, ok := s.clients[nodeName]; ok {
+		return client, nil
+	}
+
+	socketPath, err := s.resolveSocketPath(nodeName)
+	if err != nil {
+		return nil, fmt.Errorf("resolving pod-resources socket for node %q: %w", nodeName, err)
+	}
+	client, err := NewClient(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	s.clients[nodeName] = client
+	return client, nil
+}
+
+// Close releases every Client connection dialed so far.
+func (s *NodeClientSource) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for _, client := range s.clients {
+		if err := client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,16 @@
+package podresources
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNodeClientSource_PropagatesResolveError(t *testing.T) {
+	source := NewNodeClientSource(func(nodeName string) (string, error) {
+		return "", fmt.Errorf("no known socket for %s", nodeName)
+	})
+
+	if _, err := source.GetAllocatableResources("node-1"); err == nil {
+		t.Errorf("GetAllocatableResources() err = nil, want an error from resolveSocketPath")
+	}
+}
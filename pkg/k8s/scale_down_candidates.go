@@ -0,0 +1,178 @@
+package k8s
+
+import (
+	"sort"
+
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
+	v1 "k8s.io/api/core/v1"
+)
+
+// safeToEvictAnnotation mirrors the annotation used by the cluster-autoscaler to mark a
+// pod that must not be evicted by node-draining automation.
+const safeToEvictAnnotation = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+
+// mirrorPodAnnotation marks a pod as a mirror pod for a static pod, managed directly by
+// the kubelet rather than the scheduler.
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// PodIsMirror returns if the pod is a mirror pod for a static pod.
+func PodIsMirror(pod *v1.Pod) bool {
+	_, ok := pod.ObjectMeta.Annotations[mirrorPodAnnotation]
+	return ok
+}
+
+// PodIsSafeToEvict returns whether pod is allowed to be evicted by node-draining
+// automation, i.e. it isn't annotated with safe-to-evict=false.
+func PodIsSafeToEvict(pod *v1.Pod) bool {
+	return pod.ObjectMeta.Annotations[safeToEvictAnnotation] != "false"
+}
+
+// isEvictablePod returns whether pod should be considered when re-fitting the workload
+// of a scale-down candidate node onto the rest of the cluster.
+func isEvictablePod(pod *v1.Pod) bool {
+	return isPodUsingNodeResources(pod, nil) &&
+		!PodIsDaemonSet(pod) &&
+		!PodIsStatic(pod) &&
+		!PodIsMirror(pod) &&
+		PodIsSafeToEvict(pod)
+}
+
+// Thresholds defines the low/high CPU, memory and pod-count utilization ratios (0-1) used
+// to classify nodes as under- or over-utilized by SelectUnderutilizedNodes.
+type Thresholds struct {
+	Low  NodeUtilization
+	High NodeUtilization
+}
+
+// NodeCandidate is a node identified as a scale-down candidate, along with the evictable
+// pods that would need to be rescheduled onto the rest of the cluster if it were drained.
+type NodeCandidate struct {
+	Node *v1.Node
+	Pods []*v1.Pod
+}
+
+// nodeBudget tracks how much headroom a node has left before it would breach the high
+// utilization thresholds, used while simulating bin-packing evicted pods onto it.
+type nodeBudget struct {
+	node       *v1.Node
+	cpuBudget  int64
+	memBudget  int64
+	podsBudget int64
+}
+
+// SelectUnderutilizedNodes ranks nodes whose CPU, memory and pod-count utilization are all
+// below thresholds.Low as scale-down candidates, provided their evictable pods (i.e.
+// excluding DaemonSets, static pods, mirror pods and pods annotated safe-to-evict=false)
+// can be re-fit onto the remaining nodes via first-fit-decreasing bin-packing without
+// pushing any of those nodes past thresholds.High. Candidates are ranked least-utilized
+// first, and nodes are only drained of as much load as the rest of the cluster can absorb.
+func SelectUnderutilizedNodes(nodes []*v1.Node, pods []*v1.Pod, thresholds Thresholds) ([]NodeCandidate, error) {
+	capacity, err := CalculateNodesCapacity(nodes, pods, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	mappedPods := mapPods(pods)
+	var underutilized, remaining []*v1.Node
+	for _, node := range nodes {
+		util := capacity.NodeUtilization[node.Name]
+		if util.CPU < thresholds.Low.CPU && util.Memory < thresholds.Low.Memory && util.Pods < thresholds.Low.Pods {
+			underutilized = append(underutilized, node)
+		} else {
+			remaining = append(remaining, node)
+		}
+	}
+
+	// Least-utilized first, so the emptiest nodes are drained before borderline ones.
+	sort.Slice(underutilized, func(i, j int) bool {
+		a, b := capacity.NodeUtilization[underutilized[i].Name], capacity.NodeUtilization[underutilized[j].Name]
+		return a.CPU+a.Memory+a.Pods < b.CPU+b.Memory+b.Pods
+	})
+
+	budgets := nodeBudgets(remaining, mappedPods, thresholds.High)
+
+	var candidates []NodeCandidate
+	for _, node := range underutilized {
+		var evicted []*v1.Pod
+		for _, pod := range mappedPods[node.Name] {
+			if isEvictablePod(pod) {
+				evicted = append(evicted, pod)
+			}
+		}
+		if tryFit(evicted, budgets) {
+			candidates = append(candidates, NodeCandidate{Node: node, Pods: evicted})
+		}
+	}
+
+	return candidates, nil
+}
+
+// nodeBudgets computes each node's remaining headroom before it would breach high.
+func nodeBudgets(nodes []*v1.Node, mappedPods map[string][]*v1.Pod, high NodeUtilization) []*nodeBudget {
+	budgets := make([]*nodeBudget, 0, len(nodes))
+	for _, node := range nodes {
+		allocCPU := node.Status.Allocatable.Cpu().MilliValue()
+		allocMemory := node.Status.Allocatable.Memory().Value()
+		allocPods := node.Status.Allocatable.Pods().Value()
+
+		nodePods := mappedPods[node.Name]
+		usedCPU := sumByFunc(nodePods, func(pod *v1.Pod) int64 {
+			return scheduler.ComputePodResourceRequest(pod).MilliCPU
+		}, nil)
+		usedMemory := sumByFunc(nodePods, func(pod *v1.Pod) int64 {
+			return scheduler.ComputePodResourceRequest(pod).Memory
+		}, nil)
+		usedPods := countPodsUsingNodeResources(nodePods, nil)
+
+		budgets = append(budgets, &nodeBudget{
+			node:       node,
+			cpuBudget:  int64(high.CPU*float64(allocCPU)) - usedCPU,
+			memBudget:  int64(high.Memory*float64(allocMemory)) - usedMemory,
+			podsBudget: int64(high.Pods*float64(allocPods)) - usedPods,
+		})
+	}
+	return budgets
+}
+
+// tryFit attempts a first-fit-decreasing bin-packing of pods onto budgets, largest CPU
+// request first. It only commits the placement (mutating budgets) if every pod fits.
+func tryFit(pods []*v1.Pod, budgets []*nodeBudget) bool {
+	if len(pods) == 0 {
+		return true
+	}
+	if len(budgets) == 0 {
+		return false
+	}
+
+	sorted := append([]*v1.Pod(nil), pods...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return scheduler.ComputePodResourceRequest(sorted[i]).MilliCPU > scheduler.ComputePodResourceRequest(sorted[j]).MilliCPU
+	})
+
+	trial := make([]nodeBudget, len(budgets))
+	for i, b := range budgets {
+		trial[i] = *b
+	}
+
+	for _, pod := range sorted {
+		podResources := scheduler.ComputePodResourceRequest(pod)
+		placed := false
+		for i := range trial {
+			if trial[i].cpuBudget >= podResources.MilliCPU && trial[i].memBudget >= podResources.Memory && trial[i].podsBudget >= 1 {
+				trial[i].cpuBudget -= podResources.MilliCPU
+				trial[i].memBudget -= podResources.Memory
+				trial[i].podsBudget--
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			return false
+		}
+	}
+
+	for i, b := range trial {
+		*budgets[i] = b
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package k8s
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func scheduledPodOnNode(name, nodeName, cpu string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PodSpec{
+			NodeName: nodeName,
+			Containers: []v1.Container{
+				{
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)},
+					},
+				},
+			},
+		},
+		Status: v1.PodStatus{
+			Phase:      v1.PodRunning,
+			Conditions: []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}},
+		},
+	}
+}
+
+func nodeWithCPU(name, cpu string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:  resource.MustParse(cpu),
+				v1.ResourcePods: resource.MustParse("10"),
+			},
+		},
+	}
+}
+
+func TestSelectUnderutilizedNodes(t *testing.T) {
+	thresholds := Thresholds{
+		Low:  NodeUtilization{CPU: 0.2, Memory: 1, Pods: 1},
+		High: NodeUtilization{CPU: 0.8, Memory: 1, Pods: 1},
+	}
+
+	t.Run("empty node is selected and its pod fits on the remaining node", func(t *testing.T) {
+		nodes := []*v1.Node{nodeWithCPU("empty", "4"), nodeWithCPU("busy", "4")}
+		pods := []*v1.Pod{scheduledPodOnNode("pod-on-busy", "busy", "1")}
+
+		candidates, err := SelectUnderutilizedNodes(nodes, pods, thresholds)
+		if err != nil {
+			t.Fatalf("SelectUnderutilizedNodes() error = %v", err)
+		}
+		if len(candidates) != 1 || candidates[0].Node.Name != "empty" {
+			t.Fatalf("SelectUnderutilizedNodes() candidates = %+v, want just the empty node", candidates)
+		}
+	})
+
+	t.Run("nodes with pods assigned via Spec.NodeName are not misclassified as empty", func(t *testing.T) {
+		nodes := []*v1.Node{nodeWithCPU("node-a", "4"), nodeWithCPU("node-b", "4")}
+		pods := []*v1.Pod{
+			scheduledPodOnNode("pod-a", "node-a", "3.5"),
+			scheduledPodOnNode("pod-b", "node-b", "3.5"),
+		}
+
+		candidates, err := SelectUnderutilizedNodes(nodes, pods, thresholds)
+		if err != nil {
+			t.Fatalf("SelectUnderutilizedNodes() error = %v", err)
+		}
+		if len(candidates) != 0 {
+			t.Fatalf("SelectUnderutilizedNodes() candidates = %+v, want none: both nodes are heavily utilized", candidates)
+		}
+	})
+
+	t.Run("underutilized node is rejected when its pods can't be re-fit elsewhere", func(t *testing.T) {
+		nodes := []*v1.Node{nodeWithCPU("empty", "4"), nodeWithCPU("busy", "4")}
+		pods := []*v1.Pod{
+			scheduledPodOnNode("pod-on-empty", "empty", "500m"),
+			scheduledPodOnNode("pod-on-busy", "busy", "3700m"),
+		}
+
+		candidates, err := SelectUnderutilizedNodes(nodes, pods, thresholds)
+		if err != nil {
+			t.Fatalf("SelectUnderutilizedNodes() error = %v", err)
+		}
+		if len(candidates) != 0 {
+			t.Fatalf("SelectUnderutilizedNodes() candidates = %+v, want none: busy has no headroom left under the high threshold to absorb empty's pod", candidates)
+		}
+	})
+
+	t.Run("non-evictable pods on an underutilized node aren't counted against the fit check", func(t *testing.T) {
+		nodes := []*v1.Node{nodeWithCPU("empty", "4"), nodeWithCPU("busy", "4")}
+		daemonSetPod := scheduledPodOnNode("ds-pod", "empty", "100m")
+		daemonSetPod.ObjectMeta.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet"}}
+		pods := []*v1.Pod{
+			daemonSetPod,
+			scheduledPodOnNode("pod-on-busy", "busy", "3700m"),
+		}
+
+		candidates, err := SelectUnderutilizedNodes(nodes, pods, thresholds)
+		if err != nil {
+			t.Fatalf("SelectUnderutilizedNodes() error = %v", err)
+		}
+		if len(candidates) != 1 || len(candidates[0].Pods) != 0 {
+			t.Fatalf("SelectUnderutilizedNodes() candidates = %+v, want empty selected with no pods to evict, since its only pod is a DaemonSet", candidates)
+		}
+	})
+}
+
+func TestIsEvictablePod(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(pod *v1.Pod)
+		want  bool
+	}{
+		{"plain running pod is evictable", func(pod *v1.Pod) {}, true},
+		{"DaemonSet pod is not evictable", func(pod *v1.Pod) {
+			pod.ObjectMeta.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet"}}
+		}, false},
+		{"static pod is not evictable", func(pod *v1.Pod) {
+			pod.ObjectMeta.Annotations = map[string]string{"kubernetes.io/config.source": "file"}
+		}, false},
+		{"mirror pod is not evictable", func(pod *v1.Pod) {
+			pod.ObjectMeta.Annotations = map[string]string{mirrorPodAnnotation: "some-hash"}
+		}, false},
+		{"safe-to-evict=false pod is not evictable", func(pod *v1.Pod) {
+			pod.ObjectMeta.Annotations = map[string]string{safeToEvictAnnotation: "false"}
+		}, false},
+		{"safe-to-evict=true pod is evictable", func(pod *v1.Pod) {
+			pod.ObjectMeta.Annotations = map[string]string{safeToEvictAnnotation: "true"}
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := scheduledPodOnNode("pod", "node", "1")
+			tt.setup(pod)
+			if got := isEvictablePod(pod); got != tt.want {
+				t.Errorf("isEvictablePod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package scheduler computes the effective resource requests of pods, mirroring enough of
+// the kube-scheduler's own bookkeeping for escalator to reason about node fit.
+package scheduler
+
+import v1 "k8s.io/api/core/v1"
+
+// PodResource is the effective CPU/memory request of a pod.
+type PodResource struct {
+	MilliCPU int64
+	Memory   int64
+}
+
+// ComputePodResourceRequest returns pod's effective resource request: the most CPU/memory
+// the pod could need reserved on a node at any point in its lifecycle, accounting for
+// sidecar (restartable init) containers running alongside regular ones.
+func ComputePodResourceRequest(pod *v1.Pod) PodResource {
+	return PodResource{
+		MilliCPU: calculatePodResourceRequest(pod, v1.ResourceCPU),
+		Memory:   calculatePodResourceRequest(pod, v1.ResourceMemory),
+	}
+}
+
+// calculatePodResourceRequest computes pod's effective request for a single resource as the
+// larger of: regular + restartable-init containers (the steady-state main phase), or a
+// classic init container's own request plus the restartable init containers already
+// running alongside it (the init phase, since classic init containers run sequentially).
+func calculatePodResourceRequest(pod *v1.Pod, resourceName v1.ResourceName) int64 {
+	var containerSum int64
+	for _, container := range pod.Spec.Containers {
+		containerSum += requestFor(resourceName, container.Resources.Requests)
+	}
+
+	var restartableInitSum, maxClassicWithPreceding int64
+	for _, container := range pod.Spec.InitContainers {
+		request := requestFor(resourceName, container.Resources.Requests)
+		if isRestartableInitContainer(container) {
+			restartableInitSum += request
+			continue
+		}
+		if peak := request + restartableInitSum; peak > maxClassicWithPreceding {
+			maxClassicWithPreceding = peak
+		}
+	}
+
+	mainPhaseTotal := containerSum + restartableInitSum
+	if maxClassicWithPreceding > mainPhaseTotal {
+		return maxClassicWithPreceding
+	}
+	return mainPhaseTotal
+}
+
+// isRestartableInitContainer returns whether container is a sidecar: an init container
+// with restartPolicy: Always, which keeps running for the pod's lifetime.
+func isRestartableInitContainer(container v1.Container) bool {
+	return container.RestartPolicy != nil && *container.RestartPolicy == v1.ContainerRestartPolicyAlways
+}
+
+func requestFor(name v1.ResourceName, requests v1.ResourceList) int64 {
+	qty, ok := requests[name]
+	if !ok {
+		return 0
+	}
+	if name == v1.ResourceCPU {
+		return qty.MilliValue()
+	}
+	return qty.Value()
+}
@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func containerWithCPU(cpu string) v1.Container {
+	return v1.Container{
+		Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{
+				v1.ResourceCPU: resource.MustParse(cpu),
+			},
+		},
+	}
+}
+
+func sidecarWithCPU(cpu string) v1.Container {
+	always := v1.ContainerRestartPolicyAlways
+	container := containerWithCPU(cpu)
+	container.RestartPolicy = &always
+	return container
+}
+
+func TestComputePodResourceRequest(t *testing.T) {
+	tests := []struct {
+		name         string
+		pod          *v1.Pod
+		wantMilliCPU int64
+	}{
+		{
+			name: "regular containers only",
+			pod: &v1.Pod{Spec: v1.PodSpec{
+				Containers: []v1.Container{containerWithCPU("1"), containerWithCPU("2")},
+			}},
+			wantMilliCPU: 3000,
+		},
+		{
+			name: "classic init container larger than regular containers",
+			pod: &v1.Pod{Spec: v1.PodSpec{
+				Containers:     []v1.Container{containerWithCPU("1")},
+				InitContainers: []v1.Container{containerWithCPU("4")},
+			}},
+			wantMilliCPU: 4000,
+		},
+		{
+			name: "classic init container smaller than regular containers",
+			pod: &v1.Pod{Spec: v1.PodSpec{
+				Containers:     []v1.Container{containerWithCPU("1")},
+				InitContainers: []v1.Container{containerWithCPU("0.5")},
+			}},
+			wantMilliCPU: 1000,
+		},
+		{
+			name: "4-CPU sidecar adds to the running total instead of being folded into the init max",
+			pod: &v1.Pod{Spec: v1.PodSpec{
+				Containers:     []v1.Container{containerWithCPU("1")},
+				InitContainers: []v1.Container{sidecarWithCPU("4")},
+			}},
+			wantMilliCPU: 5000,
+		},
+		{
+			name: "classic init container after a sidecar adds to the sidecar instead of being covered by it",
+			pod: &v1.Pod{Spec: v1.PodSpec{
+				Containers:     []v1.Container{containerWithCPU("1")},
+				InitContainers: []v1.Container{sidecarWithCPU("4"), containerWithCPU("2")},
+			}},
+			// the sidecar (4) is still running when the classic init container (2) runs, so the
+			// peak during that window is 4+2=6, higher than the main-phase total of regular(1)+sidecar(4)=5
+			wantMilliCPU: 6000,
+		},
+		{
+			name: "classic init container after a sidecar that needs more than the sidecar provides",
+			pod: &v1.Pod{Spec: v1.PodSpec{
+				Containers:     []v1.Container{containerWithCPU("1")},
+				InitContainers: []v1.Container{sidecarWithCPU("1"), containerWithCPU("4")},
+			}},
+			// peak while the classic init container runs is sidecar(1)+classic(4)=5, higher than
+			// the main-phase total of regular(1)+sidecar(1)=2
+			wantMilliCPU: 5000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComputePodResourceRequest(tt.pod)
+			if got.MilliCPU != tt.wantMilliCPU {
+				t.Errorf("ComputePodResourceRequest().MilliCPU = %d, want %d", got.MilliCPU, tt.wantMilliCPU)
+			}
+		})
+	}
+}
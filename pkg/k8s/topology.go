@@ -0,0 +1,167 @@
+package k8s
+
+import (
+	"encoding/json"
+
+	k8s_resource "github.com/atlassian/escalator/pkg/k8s/resource"
+	"github.com/atlassian/escalator/pkg/k8s/scheduler"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// topologyAnnotation holds a NodeResourceTopology-CRD-style JSON description of a node's
+// NUMA zones, each with its own allocatable CPU/memory. Nodes without it are treated as a
+// single flat resource pool, as today.
+const topologyAnnotation = "escalator.atlassian.com/topology"
+
+// topologyZoneAnnotation, when present on a pod, names the NUMA zone it was scheduled
+// into. Only pods carrying this hint are accounted for in per-zone usage.
+const topologyZoneAnnotation = "escalator.atlassian.com/topology-zone"
+
+// ZoneCapacity is the allocatable capacity of a single NUMA zone.
+type ZoneCapacity struct {
+	Name        string
+	Allocatable ResourceItem
+}
+
+// NodeTopology is a node's NUMA zone layout, parsed from topologyAnnotation.
+type NodeTopology struct {
+	Zones []ZoneCapacity
+}
+
+type nodeTopologyZoneJSON struct {
+	Name   string `json:"name"`
+	CPU    string `json:"cpu"`
+	Memory string `json:"memory"`
+}
+
+type nodeTopologyJSON struct {
+	Zones []nodeTopologyZoneJSON `json:"zones"`
+}
+
+// ParseNodeTopology parses node's topologyAnnotation, if present. ok is false if the
+// annotation is absent, malformed, or describes no usable zones, in which case callers
+// should fall back to treating the node as a flat resource pool.
+func ParseNodeTopology(node *v1.Node) (topology *NodeTopology, ok bool) {
+	raw, present := node.ObjectMeta.Annotations[topologyAnnotation]
+	if !present {
+		return nil, false
+	}
+
+	var parsed nodeTopologyJSON
+	if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+		return nil, false
+	}
+
+	zones := make([]ZoneCapacity, 0, len(parsed.Zones))
+	for _, zone := range parsed.Zones {
+		cpu, err := resource.ParseQuantity(zone.CPU)
+		if err != nil {
+			continue
+		}
+		memory, err := resource.ParseQuantity(zone.Memory)
+		if err != nil {
+			continue
+		}
+		zones = append(zones, ZoneCapacity{
+			Name:        zone.Name,
+			Allocatable: ResourceItem{CPU: cpu, Memory: memory},
+		})
+	}
+	if len(zones) == 0 {
+		return nil, false
+	}
+
+	return &NodeTopology{Zones: zones}, true
+}
+
+// LargestFittablePodPerZone returns, for each of node's NUMA zones, the resources still
+// available in that zone after subtracting usage from scheduled pods that declare a
+// topologyZoneAnnotation hint for it. ok is false when node has no usable topology
+// annotation, in which case callers should fall back to the flat node-level calculation.
+func LargestFittablePodPerZone(node *v1.Node, pods []*v1.Pod) (available map[string]ResourceItem, ok bool) {
+	topology, ok := ParseNodeTopology(node)
+	if !ok {
+		return nil, false
+	}
+
+	used := make(map[string]ResourceItem, len(topology.Zones))
+	for _, zone := range topology.Zones {
+		used[zone.Name] = newEmptyResourceItem()
+	}
+
+	for _, pod := range pods {
+		if pod.Spec.NodeName != node.Name || !isPodUsingNodeResources(pod, nil) {
+			continue
+		}
+		zoneName, hasHint := pod.ObjectMeta.Annotations[topologyZoneAnnotation]
+		if !hasHint {
+			continue
+		}
+		item, known := used[zoneName]
+		if !known {
+			continue
+		}
+		podResources := scheduler.ComputePodResourceRequest(pod)
+		item.CPU.Add(*k8s_resource.NewCPUQuantity(podResources.MilliCPU))
+		item.Memory.Add(*k8s_resource.NewMemoryQuantity(podResources.Memory))
+		used[zoneName] = item
+	}
+
+	available = make(map[string]ResourceItem, len(topology.Zones))
+	for _, zone := range topology.Zones {
+		zoneUsed := used[zone.Name]
+		available[zone.Name] = newResourceItem(
+			zone.Allocatable.CPU.MilliValue()-zoneUsed.CPU.MilliValue(),
+			zone.Allocatable.Memory.Value()-zoneUsed.Memory.Value(),
+		)
+	}
+
+	return available, true
+}
+
+// requiresSingleZone returns whether pod needs to land entirely within one NUMA zone,
+// per the kubelet CPU Manager static policy's actual eligibility rule: Guaranteed QoS and
+// at least one container individually requesting an integer number of CPUs (limits ==
+// requests, requests.cpu a whole number). That container gets exclusive pinned cores, which
+// forces the whole pod onto a single zone - checking the pod's aggregate CPU request instead
+// would miss e.g. a 1.5+0.5 CPU pod (no container pinned, despite an even pod-level total) and
+// wrongly flag e.g. a 1+1+1.5 CPU pod (two containers pinned, despite an odd pod-level total).
+func requiresSingleZone(pod *v1.Pod) bool {
+	if pod.Status.QOSClass != v1.PodQOSGuaranteed {
+		return false
+	}
+	for _, container := range pod.Spec.Containers {
+		cpuRequest, hasRequest := container.Resources.Requests[v1.ResourceCPU]
+		cpuLimit, hasLimit := container.Resources.Limits[v1.ResourceCPU]
+		if !hasRequest || !hasLimit || cpuLimit.Cmp(cpuRequest) != 0 {
+			continue
+		}
+		if cpuRequest.MilliValue()%1000 == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CanFitPod returns whether pod can be scheduled onto node given the pods already
+// assigned to it (keyed by node name, as with GetNodeAvailableResources). When
+// node exposes NUMA topology and pod needs single-zone placement, the check is evaluated
+// per-zone so a node that looks like it has room overall isn't wrongly treated as
+// fittable when no single zone actually has enough free resources. Falls back to the flat
+// node-level calculation otherwise.
+func CanFitPod(pod *v1.Pod, node *v1.Node, pods map[string][]*v1.Pod) bool {
+	podResources := scheduler.ComputePodResourceRequest(pod)
+
+	if zoneAvailable, ok := LargestFittablePodPerZone(node, pods[node.Name]); ok && requiresSingleZone(pod) {
+		for _, zone := range zoneAvailable {
+			if podResources.MilliCPU <= zone.CPU.MilliValue() && podResources.Memory <= zone.Memory.Value() {
+				return true
+			}
+		}
+		return false
+	}
+
+	available := GetNodeAvailableResources(node, pods, nil)
+	return podResources.MilliCPU <= available.CPU.MilliValue() && podResources.Memory <= available.Memory.Value()
+}
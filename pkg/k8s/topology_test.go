@@ -0,0 +1,177 @@
+package k8s
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func guaranteedPod(cpuRequests ...string) *v1.Pod {
+	containers := make([]v1.Container, 0, len(cpuRequests))
+	for _, cpu := range cpuRequests {
+		qty := resource.MustParse(cpu)
+		containers = append(containers, v1.Container{
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: qty},
+				Limits:   v1.ResourceList{v1.ResourceCPU: qty},
+			},
+		})
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod"},
+		Spec:       v1.PodSpec{Containers: containers},
+		Status:     v1.PodStatus{QOSClass: v1.PodQOSGuaranteed},
+	}
+}
+
+func TestRequiresSingleZone_EvenPodTotalButNoIntegerContainer(t *testing.T) {
+	pod := guaranteedPod("1500m", "500m")
+
+	if requiresSingleZone(pod) {
+		t.Errorf("requiresSingleZone() = true, want false: neither container individually qualifies for exclusive-core pinning despite an even 2000m pod-level total")
+	}
+}
+
+func TestRequiresSingleZone_OddPodTotalWithIntegerContainer(t *testing.T) {
+	pod := guaranteedPod("1", "1", "1500m")
+
+	if !requiresSingleZone(pod) {
+		t.Errorf("requiresSingleZone() = false, want true: two containers individually qualify for exclusive-core pinning despite an odd 3500m pod-level total")
+	}
+}
+
+func TestRequiresSingleZone_BestEffortNeverRequiresSingleZone(t *testing.T) {
+	pod := guaranteedPod("1")
+	pod.Status.QOSClass = v1.PodQOSBestEffort
+
+	if requiresSingleZone(pod) {
+		t.Errorf("requiresSingleZone() = true, want false: only Guaranteed pods get exclusive-core pinning")
+	}
+}
+
+func nodeWithTopology(name, annotation string) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{
+			topologyAnnotation: annotation,
+		}},
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("8"),
+				v1.ResourceMemory: resource.MustParse("16Gi"),
+				v1.ResourcePods:   resource.MustParse("10"),
+			},
+		},
+	}
+}
+
+func podInZone(name, nodeName, zone, cpu string) *v1.Pod {
+	pod := guaranteedPod(cpu)
+	pod.ObjectMeta.Name = name
+	pod.ObjectMeta.Annotations = map[string]string{topologyZoneAnnotation: zone}
+	pod.Spec.NodeName = nodeName
+	pod.Status.Phase = v1.PodRunning
+	pod.Status.Conditions = []v1.PodCondition{{Type: v1.PodScheduled, Status: v1.ConditionTrue}}
+	return pod
+}
+
+func TestParseNodeTopology(t *testing.T) {
+	t.Run("valid annotation", func(t *testing.T) {
+		node := nodeWithTopology("node", `{"zones":[{"name":"zone0","cpu":"4","memory":"8Gi"},{"name":"zone1","cpu":"4","memory":"8Gi"}]}`)
+
+		topology, ok := ParseNodeTopology(node)
+		if !ok || len(topology.Zones) != 2 {
+			t.Fatalf("ParseNodeTopology() = %v, %v, want 2 zones", topology, ok)
+		}
+		if topology.Zones[0].Name != "zone0" || topology.Zones[0].Allocatable.CPU.MilliValue() != 4000 {
+			t.Errorf("ParseNodeTopology() zone0 = %+v, want cpu=4000m", topology.Zones[0])
+		}
+	})
+
+	t.Run("missing annotation falls back", func(t *testing.T) {
+		node := nodeWithCPU("node", "8")
+
+		if _, ok := ParseNodeTopology(node); ok {
+			t.Errorf("ParseNodeTopology() ok = true, want false for a node with no topology annotation")
+		}
+	})
+
+	t.Run("malformed annotation falls back", func(t *testing.T) {
+		node := nodeWithTopology("node", `not json`)
+
+		if _, ok := ParseNodeTopology(node); ok {
+			t.Errorf("ParseNodeTopology() ok = true, want false for a malformed topology annotation")
+		}
+	})
+}
+
+func TestLargestFittablePodPerZone_SubtractsPerZoneUsage(t *testing.T) {
+	node := nodeWithTopology("node", `{"zones":[{"name":"zone0","cpu":"4","memory":"8Gi"},{"name":"zone1","cpu":"4","memory":"8Gi"}]}`)
+	pods := []*v1.Pod{
+		podInZone("pod0", "node", "zone0", "3"),
+		podInZone("pod1", "node", "zone1", "1"),
+	}
+
+	available, ok := LargestFittablePodPerZone(node, pods)
+	if !ok {
+		t.Fatalf("LargestFittablePodPerZone() ok = false, want true")
+	}
+	if got := available["zone0"].CPU.MilliValue(); got != 1000 {
+		t.Errorf("LargestFittablePodPerZone() zone0 available CPU = %d, want 1000m", got)
+	}
+	if got := available["zone1"].CPU.MilliValue(); got != 3000 {
+		t.Errorf("LargestFittablePodPerZone() zone1 available CPU = %d, want 3000m", got)
+	}
+}
+
+func TestCanFitPod_SingleZoneGuaranteedPod(t *testing.T) {
+	node := nodeWithTopology("node", `{"zones":[{"name":"zone0","cpu":"4","memory":"8Gi"},{"name":"zone1","cpu":"4","memory":"8Gi"}]}`)
+
+	t.Run("no single zone has room", func(t *testing.T) {
+		existing := map[string][]*v1.Pod{
+			"node": {
+				podInZone("pod0", "node", "zone0", "3"),
+				podInZone("pod1", "node", "zone1", "3"),
+			},
+		}
+		pending := guaranteedPod("2")
+		pending.ObjectMeta.Name = "pending"
+
+		if CanFitPod(pending, node, existing) {
+			t.Errorf("CanFitPod() = true, want false: the node has 2 CPUs free overall but neither zone alone has 2 free")
+		}
+	})
+
+	t.Run("one zone has room", func(t *testing.T) {
+		existing := map[string][]*v1.Pod{
+			"node": {
+				podInZone("pod0", "node", "zone0", "3"),
+				podInZone("pod1", "node", "zone1", "1"),
+			},
+		}
+		pending := guaranteedPod("2")
+		pending.ObjectMeta.Name = "pending"
+
+		if !CanFitPod(pending, node, existing) {
+			t.Errorf("CanFitPod() = false, want true: zone1 has 3 CPUs free, enough for the pending pod")
+		}
+	})
+}
+
+func TestCanFitPod_FallsBackToFlatCalculationWithoutTopology(t *testing.T) {
+	node := nodeWithCPU("node", "4")
+	existing := map[string][]*v1.Pod{
+		"node": {scheduledPodOnNode("running", "node", "3")},
+	}
+	pending := scheduledPodOnNode("pending", "node", "1")
+
+	if !CanFitPod(pending, node, existing) {
+		t.Errorf("CanFitPod() = false, want true: node has no topology annotation so the flat 1-CPU-free calculation applies")
+	}
+
+	tooBig := scheduledPodOnNode("too-big", "node", "2")
+	if CanFitPod(tooBig, node, existing) {
+		t.Errorf("CanFitPod() = true, want false: only 1 CPU is free on the flat calculation")
+	}
+}
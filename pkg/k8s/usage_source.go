@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// UsageWindow describes an observed average and peak resource usage over a
+// rolling time window (e.g. the last 5m or 15m), as reported by a UsageSource.
+type UsageWindow struct {
+	Duration time.Duration
+	Avg      ResourceItem
+	Peak     ResourceItem
+}
+
+// NodeUsage is the actual observed usage of a node over a UsageWindow.
+type NodeUsage struct {
+	NodeName string
+	Usage    UsageWindow
+}
+
+// PodUsage is the actual observed usage of a single pod over a UsageWindow.
+type PodUsage struct {
+	Namespace string
+	Name      string
+	Usage     UsageWindow
+}
+
+// UsageSource supplies actual observed CPU/memory usage for nodes and pods,
+// e.g. backed by metrics-server or a Prometheus query layer. Implementations
+// should return an error rather than zero values when usage data cannot be
+// fetched for a node/pod, so callers can fall back to request-based
+// accounting instead of treating "no usage" as "idle".
+type UsageSource interface {
+	// NodeUsage returns the observed usage for the named node.
+	NodeUsage(nodeName string) (NodeUsage, error)
+	// PodUsage returns the observed usage for the named pod.
+	PodUsage(namespace, name string) (PodUsage, error)
+}
+
+// LoadAwareOptions configures the optional load-aware capacity accounting used by
+// CalculateNodesCapacity and GetNodeAvailableResources. Passing nil to those functions
+// preserves today's request-only behaviour.
+type LoadAwareOptions struct {
+	// Source supplies actual node/pod usage. If Source is nil, or a lookup
+	// against it fails, load-aware accounting degrades to request-only.
+	Source UsageSource
+	// DecayFactor blends a node's average and peak usage into a single
+	// "decayed" usage figure: decayed = avg + DecayFactor*(peak-avg). A
+	// DecayFactor of 0 uses the average, 1 uses the peak. Values in between
+	// bias towards peak without being as pessimistic as always using it.
+	DecayFactor float64
+	// NewPodGracePeriod is how long a scheduled pod is considered "new".
+	// Pods younger than this haven't accrued enough usage samples yet, so
+	// their declared request is used as an estimate of their usage instead.
+	NewPodGracePeriod time.Duration
+}
+
+// decayedUsage blends a UsageWindow's average and peak into a single
+// ResourceItem using the given decay factor.
+func decayedUsage(window UsageWindow, decayFactor float64) ResourceItem {
+	cpu := window.Avg.CPU.MilliValue() + int64(decayFactor*float64(window.Peak.CPU.MilliValue()-window.Avg.CPU.MilliValue()))
+	memory := window.Avg.Memory.Value() + int64(decayFactor*float64(window.Peak.Memory.Value()-window.Avg.Memory.Value()))
+	return newResourceItem(cpu, memory)
+}
+
+// podIsNew returns whether pod is too recently scheduled to have accrued
+// enough usage samples within gracePeriod.
+func podIsNew(pod *v1.Pod, gracePeriod time.Duration) bool {
+	if pod.Status.StartTime == nil {
+		return true
+	}
+	return time.Since(pod.Status.StartTime.Time) < gracePeriod
+}
@@ -0,0 +1,117 @@
+package k8s
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeUsageSource struct {
+	node    NodeUsage
+	nodeErr error
+	pod     PodUsage
+	podErr  error
+}
+
+func (f *fakeUsageSource) NodeUsage(nodeName string) (NodeUsage, error) {
+	return f.node, f.nodeErr
+}
+
+func (f *fakeUsageSource) PodUsage(namespace, name string) (PodUsage, error) {
+	return f.pod, f.podErr
+}
+
+func usageWindow(cpuMilli, memory int64) UsageWindow {
+	item := newResourceItem(cpuMilli, memory)
+	return UsageWindow{Avg: item, Peak: item}
+}
+
+func establishedPod(name, nodeName string) *v1.Pod {
+	pod := scheduledPodOnNode(name, nodeName, "1")
+	start := metav1.NewTime(time.Now().Add(-time.Hour))
+	pod.Status.StartTime = &start
+	return pod
+}
+
+func TestLoadAwareUsage_EstablishedPodUsageNotAddedOnTopOfNodeUsage(t *testing.T) {
+	node := nodeWithCPU("node", "4")
+	pod := establishedPod("pod", "node")
+
+	source := &fakeUsageSource{
+		node: NodeUsage{NodeName: "node", Usage: usageWindow(500, 500)},
+		pod:  PodUsage{Namespace: pod.Namespace, Name: pod.Name, Usage: usageWindow(2000, 2000)},
+	}
+	loadAware := &LoadAwareOptions{Source: source, DecayFactor: 0}
+
+	cpu, memory, ok := loadAwareUsage(node, []*v1.Pod{pod}, loadAware, nil)
+	if !ok {
+		t.Fatalf("loadAwareUsage() ok = false, want true")
+	}
+	if cpu != 500 || memory != 500 {
+		t.Errorf("loadAwareUsage() = (%d, %d), want just the node-level usage (500, 500) since the established pod's usage is already reflected in it", cpu, memory)
+	}
+}
+
+func TestLoadAwareUsage_NewPodRequestAddedOnTopOfLoadedNodeUsage(t *testing.T) {
+	node := nodeWithCPU("node", "4")
+	old := establishedPod("old", "node")
+	newPod := scheduledPodOnNode("new", "node", "1500m")
+
+	source := &fakeUsageSource{
+		node: NodeUsage{NodeName: "node", Usage: usageWindow(3000, 3000)},
+		pod:  PodUsage{Namespace: old.Namespace, Name: old.Name, Usage: usageWindow(500, 500)},
+	}
+	loadAware := &LoadAwareOptions{Source: source, DecayFactor: 0}
+
+	cpu, memory, ok := loadAwareUsage(node, []*v1.Pod{old, newPod}, loadAware, nil)
+	if !ok {
+		t.Fatalf("loadAwareUsage() ok = false, want true")
+	}
+	if cpu != 4500 {
+		t.Errorf("loadAwareUsage() cpu = %d, want node usage (3000) plus the new pod's estimated request (1500) = 4500", cpu)
+	}
+	if memory != 3000 {
+		t.Errorf("loadAwareUsage() memory = %d, want just the node-level usage (3000) since the new pod declares no memory request", memory)
+	}
+}
+
+func TestLoadAwareUsage_NewPodFallsBackToRequestWhenPodUsageFails(t *testing.T) {
+	node := nodeWithCPU("node", "4")
+	pod := scheduledPodOnNode("pod", "node", "1")
+
+	source := &fakeUsageSource{
+		node:   NodeUsage{NodeName: "node", Usage: usageWindow(0, 0)},
+		podErr: fmt.Errorf("no samples"),
+	}
+	loadAware := &LoadAwareOptions{Source: source, DecayFactor: 0}
+
+	cpu, _, ok := loadAwareUsage(node, []*v1.Pod{pod}, loadAware, nil)
+	if !ok {
+		t.Fatalf("loadAwareUsage() ok = false, want true")
+	}
+	if cpu != 1000 {
+		t.Errorf("loadAwareUsage() cpu = %d, want the new pod's declared request 1000", cpu)
+	}
+}
+
+func TestLoadAwareUsage_EstablishedPodNotAddedWhenPodUsageFails(t *testing.T) {
+	node := nodeWithCPU("node", "4")
+	pod := establishedPod("pod", "node")
+
+	source := &fakeUsageSource{
+		node:   NodeUsage{NodeName: "node", Usage: usageWindow(800, 800)},
+		podErr: fmt.Errorf("no samples"),
+	}
+	loadAware := &LoadAwareOptions{Source: source, DecayFactor: 0}
+
+	cpu, memory, ok := loadAwareUsage(node, []*v1.Pod{pod}, loadAware, nil)
+	if !ok {
+		t.Fatalf("loadAwareUsage() ok = false, want true")
+	}
+	if cpu != 800 || memory != 800 {
+		t.Errorf("loadAwareUsage() = (%d, %d), want just the node-level usage (800, 800): a transient per-pod lookup gap for an established pod shouldn't inflate usage by its full request", cpu, memory)
+	}
+}
@@ -27,17 +27,86 @@ type PodRequestedUsage struct {
 	Total         ResourceItem
 	LargestMemory ResourceItem
 	LargestCPU    ResourceItem
+	// LargestDevices holds, for each device resource name requested by a pending pod, the
+	// full resource profile of the single pending pod with the largest request for that
+	// device. Left nil if no pending pod requests any device resource.
+	LargestDevices map[v1.ResourceName]ResourceItem
 }
 
 type NodeAvailableCapacity struct {
 	Total                  ResourceItem
 	LargestAvailableMemory ResourceItem
 	LargestAvailableCPU    ResourceItem
+	// NodeUtilization holds the CPU/memory utilization ratio of each node,
+	// keyed by node name, for logging and scale-down candidate selection.
+	NodeUtilization map[string]NodeUtilization
+}
+
+// NodeUtilization is a node's resource usage expressed as a ratio (0-1) of
+// its allocatable capacity.
+type NodeUtilization struct {
+	CPU    float64
+	Memory float64
+	Pods   float64
 }
 
 type ResourceItem struct {
 	CPU    resource.Quantity
 	Memory resource.Quantity
+	// Devices holds non-CPU/memory resource quantities, e.g. nvidia.com/gpu, keyed by
+	// resource name. It is left nil when there are no device quantities to report, e.g. no
+	// counted pod requests a device resource, or no DeviceSource supplied node devices.
+	Devices map[v1.ResourceName]resource.Quantity
+}
+
+// CapacityOptions bundles the optional accounting modes supported by CalculateNodesCapacity
+// and GetNodeAvailableResources; CalculatePodsRequestedUsage reads only Exclusion. A nil
+// CapacityOptions, or a nil field within one, keeps today's default behaviour for that
+// dimension: pods count by their declared requests, with no extra exclusions.
+type CapacityOptions struct {
+	// LoadAware, when set, accounts for actual observed CPU/memory usage rather than
+	// just declared requests.
+	LoadAware *LoadAwareOptions
+	// Exclusion, when set, excludes additional pods from capacity accounting.
+	Exclusion *PodExclusionPolicy
+	// Devices, when set, accounts for kubelet-authoritative device allocations.
+	Devices *DeviceOptions
+}
+
+func (o *CapacityOptions) loadAware() *LoadAwareOptions {
+	if o == nil {
+		return nil
+	}
+	return o.LoadAware
+}
+
+func (o *CapacityOptions) exclusion() *PodExclusionPolicy {
+	if o == nil {
+		return nil
+	}
+	return o.Exclusion
+}
+
+func (o *CapacityOptions) devices() *DeviceOptions {
+	if o == nil {
+		return nil
+	}
+	return o.Devices
+}
+
+// withNodeLookup returns a copy of o whose Exclusion.Nodes is populated from nodes, so
+// callers of CalculateNodesCapacity don't need to separately maintain a node lookup in sync
+// with it for PodExclusionPolicy.ExcludeUnreachableTerminating. o is returned unchanged if
+// it or its Exclusion is nil.
+func (o *CapacityOptions) withNodeLookup(nodes []*v1.Node) *CapacityOptions {
+	if o == nil || o.Exclusion == nil {
+		return o
+	}
+	exclusion := *o.Exclusion
+	exclusion.Nodes = nodeLookup(nodes)
+	copied := *o
+	copied.Exclusion = &exclusion
+	return &copied
 }
 
 func newEmptyResourceItem() ResourceItem {
@@ -67,17 +136,24 @@ func newNodeAvailableCapacity() NodeAvailableCapacity {
 		Total:                  newEmptyResourceItem(),
 		LargestAvailableMemory: newEmptyResourceItem(),
 		LargestAvailableCPU:    newEmptyResourceItem(),
+		NodeUtilization:        make(map[string]NodeUtilization),
 	}
 }
 
-// CalculatePodsRequestedUsage returns the total capacity of all pods
-func CalculatePodsRequestedUsage(pods []*v1.Pod) (PodRequestedUsage, error) {
+// CalculatePodsRequestedUsage returns the total capacity of all pods. opts is optional:
+// pass nil to count every pod in pods, whatever its phase or scheduling state, by its
+// declared CPU/memory requests, as today.
+func CalculatePodsRequestedUsage(pods []*v1.Pod, opts *CapacityOptions) (PodRequestedUsage, error) {
 	ret := NewPodRequestedUsage()
 
 	for _, pod := range pods {
+		if shouldExcludePod(pod, opts.exclusion()) {
+			continue
+		}
 		podResources := scheduler.ComputePodResourceRequest(pod)
 		ret.Total.Memory.Add(*k8s_resource.NewMemoryQuantity(podResources.Memory))
 		ret.Total.CPU.Add(*k8s_resource.NewCPUQuantity(podResources.MilliCPU))
+		addDeviceRequests(&ret.Total, pod)
 		if pod.Status.Phase == v1.PodPending {
 			if podResources.Memory > ret.LargestMemory.Memory.Value() {
 				ret.LargestMemory = newResourceItem(podResources.MilliCPU, podResources.Memory)
@@ -85,21 +161,27 @@ func CalculatePodsRequestedUsage(pods []*v1.Pod) (PodRequestedUsage, error) {
 			if podResources.MilliCPU > ret.LargestCPU.CPU.MilliValue() {
 				ret.LargestCPU = newResourceItem(podResources.MilliCPU, podResources.Memory)
 			}
+			ret.LargestDevices = trackLargestDeviceRequest(ret.LargestDevices, pod, podResources)
 		}
 	}
 
 	return ret, nil
 }
 
-// CalculateNodesCapacity calculates the total Allocatable node capacity for all nodes
-func CalculateNodesCapacity(nodes []*v1.Node, pods []*v1.Pod) (NodeAvailableCapacity, error) {
+// CalculateNodesCapacity calculates the total Allocatable node capacity for all nodes.
+// opts is optional: pass nil to keep today's request-only, exclude-nothing-extra,
+// node.Status.Allocatable-only accounting.
+func CalculateNodesCapacity(nodes []*v1.Node, pods []*v1.Pod, opts *CapacityOptions) (NodeAvailableCapacity, error) {
+	opts = opts.withNodeLookup(nodes)
 	ret := newNodeAvailableCapacity()
 
 	mappedPods := mapPods(pods)
 	for _, node := range nodes {
 		ret.Total.Memory.Add(*node.Status.Allocatable.Memory())
 		ret.Total.CPU.Add(*node.Status.Allocatable.Cpu())
-		availableResource := GetNodeAvailableResources(node, mappedPods)
+		availableResource := GetNodeAvailableResources(node, mappedPods, opts)
+		podCount := countPodsUsingNodeResources(mappedPods[node.Name], opts.exclusion())
+		ret.NodeUtilization[node.Name] = nodeUtilization(node, availableResource, podCount)
 		if availableResource.CPU.MilliValue() > ret.LargestAvailableCPU.CPU.MilliValue() {
 			ret.LargestAvailableCPU = ResourceItem{
 				CPU:    *node.Status.Allocatable.Cpu(),
@@ -117,10 +199,47 @@ func CalculateNodesCapacity(nodes []*v1.Node, pods []*v1.Pod) (NodeAvailableCapa
 	return ret, nil
 }
 
+// nodeUtilization computes a node's used-capacity ratio from its allocatable
+// capacity, the resources still available on it, and the number of pods
+// scheduled to it.
+func nodeUtilization(node *v1.Node, available ResourceItem, podCount int64) NodeUtilization {
+	var util NodeUtilization
+
+	allocatableCPU := node.Status.Allocatable.Cpu().MilliValue()
+	if allocatableCPU > 0 {
+		util.CPU = 1 - float64(available.CPU.MilliValue())/float64(allocatableCPU)
+	}
+	allocatableMemory := node.Status.Allocatable.Memory().Value()
+	if allocatableMemory > 0 {
+		util.Memory = 1 - float64(available.Memory.Value())/float64(allocatableMemory)
+	}
+	allocatablePods := node.Status.Allocatable.Pods().Value()
+	if allocatablePods > 0 {
+		util.Pods = float64(podCount) / float64(allocatablePods)
+	}
+
+	return util
+}
+
+// countPodsUsingNodeResources returns how many of pods are actually occupying resources
+// on their node (i.e. scheduled and Pending/Running, and not excluded by policy).
+func countPodsUsingNodeResources(pods []*v1.Pod, policy *PodExclusionPolicy) int64 {
+	var count int64
+	for _, pod := range pods {
+		if isPodUsingNodeResources(pod, policy) {
+			count++
+		}
+	}
+	return count
+}
+
+// mapPods groups pods by the node they are actually assigned to (pod.Spec.NodeName), as
+// set by the scheduler. It is not pod.Status.NominatedNodeName, which is only populated
+// during preemption and is empty for already-running pods.
 func mapPods(pods []*v1.Pod) map[string]([]*v1.Pod) {
 	ret := make(map[string]([]*v1.Pod))
 	for _, pod := range pods {
-		name := pod.Status.NominatedNodeName
+		name := pod.Spec.NodeName
 		val, found := ret[name]
 		if !found {
 			ret[name] = make([]*v1.Pod, 0)
@@ -131,10 +250,10 @@ func mapPods(pods []*v1.Pod) map[string]([]*v1.Pod) {
 	return ret
 }
 
-func sumByFunc(pods []*v1.Pod, f func(*v1.Pod) int64) int64 {
+func sumByFunc(pods []*v1.Pod, f func(*v1.Pod) int64, policy *PodExclusionPolicy) int64 {
 	ret := int64(0)
 	for _, pod := range pods {
-		if isPodUsingNodeResources(pod) {
+		if isPodUsingNodeResources(pod, policy) {
 			ret += f(pod)
 		}
 	}
@@ -150,22 +269,83 @@ func isPodScheduled(pod *v1.Pod) bool {
 	return false
 }
 
-func isPodUsingNodeResources(pod *v1.Pod) bool {
+// isPodUsingNodeResources returns whether pod should be counted as occupying resources on
+// its node: scheduled, Pending or Running, and not excluded by policy.
+func isPodUsingNodeResources(pod *v1.Pod, policy *PodExclusionPolicy) bool {
 	return isPodScheduled(pod) &&
-		(pod.Status.Phase == v1.PodPending || pod.Status.Phase == v1.PodRunning)
+		(pod.Status.Phase == v1.PodPending || pod.Status.Phase == v1.PodRunning) &&
+		!shouldExcludePod(pod, policy)
 }
 
-func GetNodeAvailableResources(node *v1.Node, pods map[string]([]*v1.Pod)) ResourceItem {
-	filteredPods := pods[node.Name] // We are not 100% that this maps to pod.Status.NominatedNodeName so reviewer expertise would be appreciated here
-	usedCpu := sumByFunc(filteredPods, func(pod *v1.Pod) int64 {
+// GetNodeAvailableResources returns the resources still available on node given the pods
+// assigned to it. opts is optional: pass nil to keep today's request-only,
+// exclude-nothing-extra, node.Status.Allocatable-only accounting.
+func GetNodeAvailableResources(node *v1.Node, pods map[string]([]*v1.Pod), opts *CapacityOptions) ResourceItem {
+	filteredPods := pods[node.Name]
+	exclusionPolicy := opts.exclusion()
+	requestedCpu := sumByFunc(filteredPods, func(pod *v1.Pod) int64 {
 		podResources := scheduler.ComputePodResourceRequest(pod)
 		return podResources.MilliCPU
-	})
-	usedMemory := sumByFunc(filteredPods, func(pod *v1.Pod) int64 {
+	}, exclusionPolicy)
+	requestedMemory := sumByFunc(filteredPods, func(pod *v1.Pod) int64 {
 		podResources := scheduler.ComputePodResourceRequest(pod)
 		return podResources.Memory
-	})
-	return newResourceItem(node.Status.Allocatable.Cpu().MilliValue()-usedCpu,
-		node.Status.Allocatable.Memory().Value()-usedMemory)
+	}, exclusionPolicy)
+
+	usedCpu, usedMemory := requestedCpu, requestedMemory
+	if actualCpu, actualMemory, ok := loadAwareUsage(node, filteredPods, opts.loadAware(), exclusionPolicy); ok {
+		if actualCpu > usedCpu {
+			usedCpu = actualCpu
+		}
+		if actualMemory > usedMemory {
+			usedMemory = actualMemory
+		}
+	}
+
+	allocatableCpu := node.Status.Allocatable.Cpu().MilliValue()
+	allocatableMemory := node.Status.Allocatable.Memory().Value()
+	devices, cpuOverride, hasCPUOverride, memoryOverride, hasMemoryOverride := deviceAvailability(node, filteredPods, opts.devices(), exclusionPolicy)
+	if hasCPUOverride {
+		allocatableCpu = cpuOverride
+	}
+	if hasMemoryOverride {
+		allocatableMemory = memoryOverride
+	}
+
+	result := newResourceItem(allocatableCpu-usedCpu, allocatableMemory-usedMemory)
+	result.Devices = devices
+
+	return result
+}
+
+// loadAwareUsage returns node's decayed actual usage plus the declared request of each pod
+// too recently scheduled to have accrued real samples yet (per podIsNew). Established pods
+// are assumed to already be reflected in the node-level figure, whether or not a per-pod
+// usage sample happens to be available for them - a transient per-pod lookup gap shouldn't
+// inflate an otherwise-idle, over-provisioned pod's contribution by its full request. ok is
+// false whenever loadAware doesn't apply, in which case callers should keep using
+// request-based accounting.
+func loadAwareUsage(node *v1.Node, pods []*v1.Pod, loadAware *LoadAwareOptions, exclusionPolicy *PodExclusionPolicy) (cpu int64, memory int64, ok bool) {
+	if loadAware == nil || loadAware.Source == nil {
+		return 0, 0, false
+	}
+
+	nodeUsage, err := loadAware.Source.NodeUsage(node.Name)
+	if err != nil {
+		return 0, 0, false
+	}
+	decayed := decayedUsage(nodeUsage.Usage, loadAware.DecayFactor)
+	cpu = decayed.CPU.MilliValue()
+	memory = decayed.Memory.Value()
+
+	for _, pod := range pods {
+		if !isPodUsingNodeResources(pod, exclusionPolicy) || !podIsNew(pod, loadAware.NewPodGracePeriod) {
+			continue
+		}
+		podResources := scheduler.ComputePodResourceRequest(pod)
+		cpu += podResources.MilliCPU
+		memory += podResources.Memory
+	}
 
+	return cpu, memory, true
 }